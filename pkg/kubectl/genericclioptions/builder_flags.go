@@ -17,10 +17,24 @@ limitations under the License.
 package genericclioptions
 
 import (
+	"fmt"
+
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/kubernetes/pkg/kubectl/genericclioptions/resource"
 )
 
+// ContextAnnotation is set on every resource.Info returned while fanning a
+// query out across multiple kubeconfig contexts, so printers can group or
+// label output by the cluster it came from.
+const ContextAnnotation = "kubectl.kubernetes.io/context"
+
 // ResourceBuilderFlags are flags for finding resources
 // TODO(juanvallejo): wire --local flag from commands through
 type ResourceBuilderFlags struct {
@@ -30,12 +44,29 @@ type ResourceBuilderFlags struct {
 	FieldSelector *string
 	AllNamespaces *bool
 
+	// Contexts is the set of kubeconfig contexts to run the same query
+	// against. When non-empty (or AllContexts is true), ToBuilder fans
+	// the selector/filename/label/field query out across each named
+	// context and chains the results into a single resource.Visitor.
+	Contexts *[]string
+	// AllContexts expands Contexts to every context defined in the
+	// kubeconfig loaded by restClientGetter.
+	AllContexts *bool
+
+	// ClientGetterForContext returns a RESTClientGetter scoped to a
+	// single kubeconfig context, derived from restClientGetter. It
+	// defaults to overriding the current context on the raw config
+	// loader; callers with their own per-context plumbing (e.g.
+	// ConfigFlags) may replace it.
+	ClientGetterForContext func(restClientGetter RESTClientGetter, context string) (RESTClientGetter, error)
+
 	All bool
 }
 
 // NewResourceBuilderFlags returns a default ResourceBuilderFlags
 func NewResourceBuilderFlags() *ResourceBuilderFlags {
 	filenames := []string{}
+	contexts := []string{}
 
 	return &ResourceBuilderFlags{
 		FileNameFlags: &FileNameFlags{
@@ -44,8 +75,11 @@ func NewResourceBuilderFlags() *ResourceBuilderFlags {
 			Recursive: boolPtr(true),
 		},
 
-		LabelSelector: strPtr(""),
-		AllNamespaces: boolPtr(false),
+		LabelSelector:          strPtr(""),
+		AllNamespaces:          boolPtr(false),
+		Contexts:               &contexts,
+		AllContexts:            boolPtr(false),
+		ClientGetterForContext: restClientGetterForContext,
 	}
 }
 
@@ -67,19 +101,56 @@ func (o *ResourceBuilderFlags) AddFlags(flagset *pflag.FlagSet) {
 	if o.AllNamespaces != nil {
 		flagset.BoolVar(o.AllNamespaces, "all-namespaces", *o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
 	}
+	if o.Contexts != nil {
+		flagset.StringSliceVar(o.Contexts, "contexts", *o.Contexts, "One or more kubeconfig contexts to run this command against, fanning the request out to each cluster. Takes precedence over the current context.")
+	}
+	if o.AllContexts != nil {
+		flagset.BoolVar(o.AllContexts, "all-contexts", *o.AllContexts, "If present, run this command against every context in the kubeconfig, in addition to any specified with --contexts.")
+	}
 }
 
 // ToBuilder gives you back a resource finder to visit resources that are located
 func (o *ResourceBuilderFlags) ToBuilder(restClientGetter RESTClientGetter, resources []string) ResourceFinder {
+	contexts := o.contextsFor(restClientGetter)
+	if len(contexts) == 0 {
+		return &ResourceFindBuilderWrapper{
+			builder: o.builderForContext(restClientGetter, resources),
+		}
+	}
+
+	finder := &multiClusterResourceFinder{}
+	for _, context := range contexts {
+		contextClientGetter, err := o.ClientGetterForContext(restClientGetter, context)
+		if err != nil {
+			finder.errs = append(finder.errs, fmt.Errorf("context %q: %v", context, err))
+			continue
+		}
+		finder.perContext = append(finder.perContext, taggedBuilder{
+			context: context,
+			builder: &ResourceFindBuilderWrapper{builder: o.builderForContext(contextClientGetter, resources)},
+		})
+	}
+	return finder
+}
+
+// builderForContext constructs the resource.Builder used to enumerate
+// resources against a single cluster, honoring the selector, filename and
+// namespace flags shared by every context.
+func (o *ResourceBuilderFlags) builderForContext(restClientGetter RESTClientGetter, resources []string) *resource.Builder {
 	namespace, enforceNamespace, namespaceErr := restClientGetter.ToRawKubeConfigLoader().Namespace()
 
 	builder := resource.NewBuilder(restClientGetter).
 		Unstructured().
 		NamespaceParam(namespace).DefaultNamespace().
 		ResourceTypeOrNameArgs(o.All, resources...)
+	var fileNameErr error
 	if o.FileNameFlags != nil {
-		opts := o.FileNameFlags.ToOptions()
-		builder = builder.FilenameParam(enforceNamespace, &opts)
+		opts, err := o.FileNameFlags.ToOptions()
+		if err != nil {
+			fileNameErr = err
+		} else {
+			builder = builder.FilenameParam(enforceNamespace, &opts)
+		}
 	}
 	if o.LabelSelector != nil {
 		builder = builder.LabelSelectorParam(*o.LabelSelector)
@@ -88,12 +159,176 @@ func (o *ResourceBuilderFlags) ToBuilder(restClientGetter RESTClientGetter, reso
 		builder = builder.FieldSelectorParam(*o.FieldSelector)
 	}
 
-	return &ResourceFindBuilderWrapper{
-		builder: builder.
-			Latest().
-			Flatten().
-			AddError(namespaceErr),
+	return builder.
+		Latest().
+		Flatten().
+		AddError(namespaceErr).
+		AddError(fileNameErr)
+}
+
+// contextsFor returns the deduplicated list of kubeconfig contexts this
+// query should fan out against, or an empty list if it should just use the
+// context restClientGetter is already scoped to.
+func (o *ResourceBuilderFlags) contextsFor(restClientGetter RESTClientGetter) []string {
+	seen := map[string]bool{}
+	contexts := []string{}
+	if o.Contexts != nil {
+		for _, context := range *o.Contexts {
+			if !seen[context] {
+				seen[context] = true
+				contexts = append(contexts, context)
+			}
+		}
+	}
+	if o.AllContexts != nil && *o.AllContexts {
+		rawConfig, err := restClientGetter.ToRawKubeConfigLoader().RawConfig()
+		if err == nil {
+			for context := range rawConfig.Contexts {
+				if !seen[context] {
+					seen[context] = true
+					contexts = append(contexts, context)
+				}
+			}
+		}
+	}
+	return contexts
+}
+
+// restClientGetterForContext is the default ClientGetterForContext. If
+// restClientGetter already knows how to scope itself to another context
+// (e.g. a ConfigFlags that implements contextOverrider) it defers to that;
+// otherwise it falls back to loading restClientGetter's raw kubeconfig and
+// building an independent RESTClientGetter from it with CurrentContext
+// overridden, reusing whatever precedence rules (explicit file, merge, env)
+// that raw loader already applied.
+func restClientGetterForContext(restClientGetter RESTClientGetter, context string) (RESTClientGetter, error) {
+	if getter, ok := restClientGetter.(contextOverrider); ok {
+		return getter.WithContext(context), nil
+	}
+
+	rawConfig, err := restClientGetter.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading raw kubeconfig to override context %q: %v", context, err)
+	}
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(rawConfig, context, &clientcmd.ConfigOverrides{CurrentContext: context}, nil)
+	if _, err := clientConfig.ClientConfig(); err != nil {
+		return nil, fmt.Errorf("resolving context %q: %v", context, err)
+	}
+	return &contextClientGetter{clientConfig: clientConfig}, nil
+}
+
+// contextOverrider is implemented by RESTClientGetters (such as ConfigFlags)
+// that know how to return a copy of themselves scoped to a different
+// kubeconfig context.
+type contextOverrider interface {
+	WithContext(context string) RESTClientGetter
+}
+
+// contextClientGetter is the RESTClientGetter restClientGetterForContext
+// falls back to building itself: a clientcmd.ClientConfig pinned to a
+// single context, with discovery and REST mapping derived from that
+// context's own REST config rather than whatever cluster the original
+// restClientGetter was talking to.
+type contextClientGetter struct {
+	clientConfig clientcmd.ClientConfig
+}
+
+// ToRESTConfig implements RESTClientGetter.
+func (g *contextClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return g.clientConfig.ClientConfig()
+}
+
+// ToRawKubeConfigLoader implements RESTClientGetter.
+func (g *contextClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
+
+// ToDiscoveryClient implements RESTClientGetter.
+func (g *contextClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	config, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(discoveryClient), nil
+}
+
+// ToRESTMapper implements RESTClientGetter.
+func (g *contextClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	discoveryClient, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+	return restmapper.NewShortcutExpander(mapper, discoveryClient), nil
+}
+
+// taggedBuilder pairs a per-cluster ResourceFinder with the context name it
+// was built for, so results can be tagged as they're visited.
+type taggedBuilder struct {
+	context string
+	builder ResourceFinder
+}
+
+// multiClusterResourceFinder fans a query out across several kubeconfig
+// contexts and chains their visitors into one, tagging each visited
+// resource.Info with the context it came from via ContextAnnotation.
+// Per-context errors are collected rather than treated as fatal, so one
+// unreachable cluster doesn't prevent results from the others.
+type multiClusterResourceFinder struct {
+	perContext []taggedBuilder
+	errs       []error
+}
+
+// Do implements ResourceFinder
+func (f *multiClusterResourceFinder) Do() resource.Visitor {
+	visitors := make([]visitorFunc, 0, len(f.perContext))
+	for _, tb := range f.perContext {
+		context := tb.context
+		builder := tb.builder
+		visitors = append(visitors, func(fn resource.VisitorFunc) error {
+			return builder.Do().Visit(func(info *resource.Info, err error) error {
+				if err != nil {
+					return err
+				}
+				if accessor, accessorErr := meta.Accessor(info.Object); accessorErr == nil {
+					annotations := accessor.GetAnnotations()
+					if annotations == nil {
+						annotations = map[string]string{}
+					}
+					annotations[ContextAnnotation] = context
+					accessor.SetAnnotations(annotations)
+				}
+				return fn(info, nil)
+			})
+		})
 	}
+
+	aggregate := errors.NewAggregate(f.errs)
+	return visitorFunc(func(fn resource.VisitorFunc) error {
+		var visitErrs []error
+		if aggregate != nil {
+			visitErrs = append(visitErrs, aggregate)
+		}
+		for _, v := range visitors {
+			if err := v(fn); err != nil {
+				visitErrs = append(visitErrs, err)
+			}
+		}
+		return errors.NewAggregate(visitErrs)
+	})
+}
+
+// visitorFunc adapts a plain func into a resource.Visitor, the same way
+// resource.VisitorFunc adapts a plain func into the per-Info callback.
+type visitorFunc func(resource.VisitorFunc) error
+
+// Visit implements resource.Visitor
+func (f visitorFunc) Visit(fn resource.VisitorFunc) error {
+	return f(fn)
 }
 
 // ResourceFindBuilderWrapper wraps a builder in an interface
@@ -0,0 +1,403 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericclioptions
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/kubernetes/pkg/kubectl/genericclioptions/resource"
+)
+
+// DiffType describes how a locally-visited object compares to its live
+// counterpart on the server.
+type DiffType string
+
+const (
+	// DiffTypeAdded means the object exists locally but not on the server.
+	DiffTypeAdded DiffType = "Added"
+	// DiffTypeRemoved means the object exists on the server but the local
+	// visit didn't produce it (only meaningful when diffing a whole
+	// namespace rather than a single manifest).
+	DiffTypeRemoved DiffType = "Removed"
+	// DiffTypeChanged means both copies exist and differ.
+	DiffTypeChanged DiffType = "Changed"
+	// DiffTypeUnchanged means both copies exist and are identical.
+	DiffTypeUnchanged DiffType = "Unchanged"
+)
+
+// DiffResult is the outcome of comparing one visited resource.Info against
+// the live object on the server.
+type DiffResult struct {
+	Info *resource.Info
+	Type DiffType
+
+	// Patch is the strategic merge patch that would take the live object
+	// to the local one; empty for Added, Removed and Unchanged results.
+	Patch []byte
+
+	// Text is Patch under "--- live" / "+++ local" header lines, as plain
+	// text. It is not colorized and is not a line-by-line unified diff.
+	Text string
+}
+
+// DiffOptions is a ResourceBuilderFlags companion that, given the same
+// selector/filename/namespace flags, streams a DiffResult for every object
+// it visits instead of (or in addition to) the object itself. It's meant to
+// be embedded by any command that needs to compare local manifests against
+// the live cluster -- today that's `kubectl diff`, but the visitor itself
+// is reusable by plugins that want the same semantics.
+type DiffOptions struct {
+	*ResourceBuilderFlags
+}
+
+// NewDiffOptions returns a default DiffOptions built on top of a default
+// ResourceBuilderFlags.
+func NewDiffOptions() *DiffOptions {
+	return &DiffOptions{
+		ResourceBuilderFlags: NewResourceBuilderFlags(),
+	}
+}
+
+// ToDiffer gives you back a Differ that streams a DiffResult for every
+// object identified by the shared selector/filename/namespace flags. The
+// same flags also scope the live-side listing DiffVisitor uses to detect
+// DiffTypeRemoved objects and the informers Watch starts for drift mode.
+// When Contexts/AllContexts fan the query out across multiple kubeconfig
+// contexts, ToDiffer builds a dynamic client per context so DiffVisitor can
+// detect removals against each cluster the local manifests were diffed
+// against, not just the one restClientGetter happened to be scoped to.
+func (o *DiffOptions) ToDiffer(restClientGetter RESTClientGetter, resources []string) (*Differ, error) {
+	labelSelector, fieldSelector := "", ""
+	if o.LabelSelector != nil {
+		labelSelector = *o.LabelSelector
+	}
+	if o.FieldSelector != nil {
+		fieldSelector = *o.FieldSelector
+	}
+
+	clusters := map[string]diffCluster{}
+	contexts := o.contextsFor(restClientGetter)
+	if len(contexts) == 0 {
+		cluster, err := diffClusterFor(restClientGetter)
+		if err != nil {
+			return nil, err
+		}
+		clusters[""] = cluster
+	} else {
+		for _, context := range contexts {
+			contextClientGetter, err := o.ClientGetterForContext(restClientGetter, context)
+			if err != nil {
+				return nil, fmt.Errorf("context %q: %v", context, err)
+			}
+			cluster, err := diffClusterFor(contextClientGetter)
+			if err != nil {
+				return nil, fmt.Errorf("context %q: %v", context, err)
+			}
+			clusters[context] = cluster
+		}
+	}
+
+	return &Differ{
+		finder:        o.ToBuilder(restClientGetter, resources),
+		clusters:      clusters,
+		allNamespaces: o.AllNamespaces != nil && *o.AllNamespaces,
+		labelSelector: labelSelector,
+		fieldSelector: fieldSelector,
+	}, nil
+}
+
+// diffCluster is the per-context live-side state a Differ needs: a dynamic
+// client to list/watch against, and the namespace that context's kubeconfig
+// resolves to.
+type diffCluster struct {
+	dynamicClient dynamic.Interface
+	namespace     string
+}
+
+func diffClusterFor(restClientGetter RESTClientGetter) (diffCluster, error) {
+	config, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return diffCluster{}, fmt.Errorf("building diff client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return diffCluster{}, fmt.Errorf("building diff client: %v", err)
+	}
+	namespace, _, err := restClientGetter.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return diffCluster{}, err
+	}
+	return diffCluster{dynamicClient: dynamicClient, namespace: namespace}, nil
+}
+
+// Differ streams a DiffResult per visited object by reusing a
+// ResourceFinder to enumerate both the local and remote side of each
+// resource. It implements the same Do() contract as a plain ResourceFinder,
+// so `kubectl diff`, `kubectl apply`, and third-party plugins can share one
+// code path, calling DiffVisitor instead of (or alongside) their own
+// visitor when they want structured diff results rather than raw objects.
+type Differ struct {
+	finder ResourceFinder
+
+	// clusters holds one diffCluster per kubeconfig context the finder
+	// fans queries out across, keyed by context name. A Differ built
+	// against a single context (no --contexts/--all-contexts) has exactly
+	// one entry keyed by "".
+	clusters      map[string]diffCluster
+	allNamespaces bool
+	labelSelector string
+	fieldSelector string
+}
+
+// contextOf returns the kubeconfig context info was visited under, per the
+// ContextAnnotation multiClusterResourceFinder tags it with, or "" for a
+// single-context Differ.
+func contextOf(info *resource.Info) string {
+	accessor, err := meta.Accessor(info.Object)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetAnnotations()[ContextAnnotation]
+}
+
+// Do implements ResourceFinder by returning the underlying finder's visitor
+// unchanged; it fetches nothing from the server and computes no diff.
+// Callers that want the structured diff should call DiffVisitor instead.
+func (d *Differ) Do() resource.Visitor {
+	return d.finder.Do()
+}
+
+// DiffVisitor walks every object the Differ's finder visits, fetches its
+// live counterpart from the server, and invokes fn with the resulting
+// DiffResult. A local object with no live counterpart is reported as
+// DiffTypeAdded rather than treated as an error.
+//
+// Once the local visit completes, DiffVisitor also lists the live objects
+// of every GroupVersionResource it encountered (honoring the same
+// namespace/label/field selectors the local visit used) and reports any
+// that weren't among the visited objects as DiffTypeRemoved. This is what
+// lets `kubectl diff --all-namespaces -l app=foo` surface objects that
+// exist on the server but were dropped from the local manifests.
+func (d *Differ) DiffVisitor(fn func(DiffResult) error) error {
+	// visited is keyed by context first (see contextOf), then GVR, then
+	// namespace/name, so reportRemoved can list each GVR against the
+	// right cluster instead of only ever the one restClientGetter was
+	// scoped to.
+	visited := map[string]map[schema.GroupVersionResource]map[string]bool{}
+
+	err := d.finder.Do().Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+
+		context := contextOf(info)
+		if visited[context] == nil {
+			visited[context] = map[schema.GroupVersionResource]map[string]bool{}
+		}
+		gvr := info.Mapping.Resource
+		if visited[context][gvr] == nil {
+			visited[context][gvr] = map[string]bool{}
+		}
+		visited[context][gvr][diffKey(info.Namespace, info.Name)] = true
+
+		result, diffErr := diffAgainstLive(info)
+		if diffErr != nil {
+			return diffErr
+		}
+		return fn(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.reportRemoved(visited, fn)
+}
+
+// reportRemoved lists, for every context a diffCluster was built for, the
+// live objects of every GVR visited under that context, and invokes fn with
+// a DiffTypeRemoved result for each one whose namespace/name isn't in the
+// corresponding visited set.
+func (d *Differ) reportRemoved(visited map[string]map[schema.GroupVersionResource]map[string]bool, fn func(DiffResult) error) error {
+	for context, cluster := range d.clusters {
+		namespace := cluster.namespace
+		if d.allNamespaces {
+			namespace = ""
+		}
+
+		for gvr, names := range visited[context] {
+			list, err := cluster.dynamicClient.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{
+				LabelSelector: d.labelSelector,
+				FieldSelector: d.fieldSelector,
+			})
+			if err != nil {
+				return fmt.Errorf("listing live %s to detect removals: %v", gvr.Resource, err)
+			}
+			for i := range list.Items {
+				live := &list.Items[i]
+				if names[diffKey(live.GetNamespace(), live.GetName())] {
+					continue
+				}
+				if err := fn(DiffResult{
+					Info: &resource.Info{Namespace: live.GetNamespace(), Name: live.GetName(), Object: live},
+					Type: DiffTypeRemoved,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func diffKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// diffAgainstLive fetches the live copy of info's object and computes the
+// DiffResult between it and the locally-visited copy.
+func diffAgainstLive(info *resource.Info) (DiffResult, error) {
+	helper := resource.NewHelper(info.Client, info.Mapping)
+
+	live, err := helper.Get(info.Namespace, info.Name, false)
+	if errors.IsNotFound(err) {
+		return DiffResult{Info: info, Type: DiffTypeAdded}, nil
+	}
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("fetching live copy of %s/%s: %v", info.Mapping.Resource.Resource, info.Name, err)
+	}
+
+	return buildDiffResult(info, live)
+}
+
+// buildDiffResult computes the DiffResult between info's locally-visited
+// object and an already-fetched liveObj. It's split out from
+// diffAgainstLive so Watch can reuse it with the live object an informer
+// event delivered, rather than fetching it again.
+func buildDiffResult(info *resource.Info, liveObj runtime.Object) (DiffResult, error) {
+	liveJSON, err := json.Marshal(liveObj)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("encoding live copy of %s: %v", info.Name, err)
+	}
+	localJSON, err := json.Marshal(info.Object)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("encoding local copy of %s: %v", info.Name, err)
+	}
+
+	patch, err := strategicpatch.CreateTwoWayMergePatch(liveJSON, localJSON, info.Object)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("diffing %s: %v", info.Name, err)
+	}
+
+	if string(patch) == "{}" {
+		return DiffResult{Info: info, Type: DiffTypeUnchanged}, nil
+	}
+	return DiffResult{
+		Info:  info,
+		Type:  DiffTypeChanged,
+		Patch: patch,
+		Text:  unifiedDiffText(liveJSON, localJSON, patch),
+	}, nil
+}
+
+// Watch puts the Differ into "drift watch" mode: it does one pass over the
+// local manifests to cache what they looked like, then starts one informer
+// per GVR the finder matches (reusing the same discovery and
+// selector/namespace scoping as StreamingResourceFinder) and re-diffs the
+// cached local copy against whatever live object each informer event
+// delivers. Unlike DiffVisitor this never re-fetches the live side -- the
+// informer event already carries it. The returned channel is closed, and
+// the informers stopped, when stopCh is closed.
+//
+// Watch does not support a Differ built against multiple kubeconfig
+// contexts (--contexts/--all-contexts): unlike DiffVisitor, which diffs
+// each context's objects against their own cluster, a single set of
+// informers can only watch one cluster. Run Watch separately per context
+// instead of asking it to silently cover just one of them.
+func (d *Differ) Watch(stopCh <-chan struct{}) (<-chan DiffResult, error) {
+	if len(d.clusters) != 1 {
+		return nil, fmt.Errorf("diff watch does not support multiple kubeconfig contexts (--contexts/--all-contexts); run it once per context instead")
+	}
+	var cluster diffCluster
+	for _, c := range d.clusters {
+		cluster = c
+	}
+
+	local := map[string]*resource.Info{}
+	err := d.finder.Do().Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		local[diffKey(info.Namespace, info.Name)] = info
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	streaming := &streamingResourceFinder{
+		ResourceFinder: d.finder,
+		dynamicClient:  cluster.dynamicClient,
+		namespace:      cluster.namespace,
+		allNamespaces:  d.allNamespaces,
+		labelSelector:  d.labelSelector,
+		fieldSelector:  d.fieldSelector,
+		watchOpts:      NewWatchOptions(),
+	}
+	events, err := streaming.Watch(stopCh)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan DiffResult, streaming.watchOpts.EventBufferSize)
+	go func() {
+		defer close(out)
+		for event := range events {
+			info, tracked := local[diffKey(event.Info.Namespace, event.Info.Name)]
+			if !tracked {
+				// Only drift on objects the local manifests actually
+				// named; anything else is noise from a broad selector.
+				continue
+			}
+			if event.Type == watch.Deleted {
+				out <- DiffResult{Info: info, Type: DiffTypeRemoved}
+				continue
+			}
+			result, err := buildDiffResult(info, event.Info.Object)
+			if err != nil {
+				continue
+			}
+			out <- result
+		}
+	}()
+	return out, nil
+}
+
+// unifiedDiffText renders patch as plain text under "--- live" and
+// "+++ local" header lines. It prints the strategic merge patch as is: it
+// adds no color and does not diff liveJSON against localJSON line by line.
+func unifiedDiffText(liveJSON, localJSON, patch []byte) string {
+	return fmt.Sprintf("--- live\n+++ local\n%s", string(patch))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericclioptions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToOptionsRejectsKustomizeWithFilenames(t *testing.T) {
+	filenames := []string{"pod.yaml"}
+	kustomize := "./overlay"
+	flags := &FileNameFlags{Filenames: &filenames, Kustomize: &kustomize}
+
+	_, err := flags.ToOptions()
+	if err == nil {
+		t.Fatal("expected an error combining -f with -k, got nil")
+	}
+	if !strings.Contains(err.Error(), "can't be used together") {
+		t.Errorf("error = %q, want it to mention -f/-k can't be combined", err.Error())
+	}
+}
+
+func TestToOptionsPassesThroughFilenames(t *testing.T) {
+	filenames := []string{"pod.yaml", "svc.yaml"}
+	recursive := true
+	flags := &FileNameFlags{Filenames: &filenames, Recursive: &recursive}
+
+	opts, err := flags.ToOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.Recursive {
+		t.Errorf("Recursive = false, want true")
+	}
+	if len(opts.Filenames) != 2 || opts.Filenames[0] != "pod.yaml" || opts.Filenames[1] != "svc.yaml" {
+		t.Errorf("Filenames = %v, want [pod.yaml svc.yaml]", opts.Filenames)
+	}
+}
+
+func TestRenderOverlaySurfacesErrorDirectly(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := RenderOverlay(dir)
+	if err == nil {
+		t.Fatal("expected an error for a directory with no kustomization.yaml, got nil")
+	}
+	if !strings.Contains(err.Error(), dir) {
+		t.Errorf("error = %q, want it to name the path %q instead of a synthetic filename", err.Error(), dir)
+	}
+}
+
+func TestToOptionsSurfacesRenderErrorDirectly(t *testing.T) {
+	dir := t.TempDir()
+	flags := &FileNameFlags{Kustomize: &dir}
+
+	_, err := flags.ToOptions()
+	if err == nil {
+		t.Fatal("expected a render error, got nil")
+	}
+	if !strings.Contains(err.Error(), dir) {
+		t.Errorf("error = %q, want it to name the overlay path %q rather than a synthetic filename", err.Error(), dir)
+	}
+}
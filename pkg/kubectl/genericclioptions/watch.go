@@ -0,0 +1,358 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericclioptions
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/kubectl/genericclioptions/resource"
+)
+
+// pollFallbackKey identifies an object across successive polls, independent
+// of resourceVersion, so List snapshots can be diffed into Added/Modified/
+// Deleted events.
+type pollFallbackKey struct {
+	namespace string
+	name      string
+}
+
+// WatchEvent is a single add/update/delete observed by a
+// StreamingResourceFinder.
+type WatchEvent struct {
+	Type watch.EventType
+	Info *resource.Info
+}
+
+// StreamingResourceFinder is implemented by ResourceFinders that additionally
+// know how to stream add/update/delete events via shared informers, rather
+// than only supporting a one-shot resource.Visitor. Commands like `kubectl
+// get -w` and `kubectl top`, and third-party plugins, can use this instead
+// of each re-implementing list+watch from scratch.
+type StreamingResourceFinder interface {
+	ResourceFinder
+
+	// Watch starts one shared informer per GVR matched by the finder's
+	// selector/namespace flags and returns a channel of events
+	// multiplexed across all of them. The channel is closed, and the
+	// informers stopped, when stopCh is closed. A GVR whose informer
+	// hasn't synced within WatchOptions.WatchTimeout -- typically because
+	// the apiserver doesn't support watch on that resource -- falls back
+	// to polling it with List every WatchOptions.PollInterval instead.
+	Watch(stopCh <-chan struct{}) (<-chan WatchEvent, error)
+}
+
+// WatchOptions configures a StreamingResourceFinder. Build one with
+// NewWatchOptions, which fills in the defaults AddFlags assumes; the zero
+// value is not usable, since WatchTimeout, PollInterval and EventBufferSize
+// must all be positive.
+type WatchOptions struct {
+	// ResyncPeriod is how often each informer does a full relist against
+	// the apiserver, independent of the events it's streaming.
+	ResyncPeriod time.Duration
+
+	// EventBufferSize bounds the channel Watch returns. Once full,
+	// further events are dropped and counted in Dropped rather than
+	// blocking the informers.
+	EventBufferSize int
+
+	// Dropped is incremented every time an event is dropped because the
+	// channel returned by Watch was full.
+	Dropped int64
+
+	// WatchTimeout bounds how long Watch waits for a GVR's informer to
+	// complete its initial sync. If the apiserver doesn't support watch on
+	// that resource, the informer's ListAndWatch never returns a successful
+	// watch and HasSynced never becomes true, so without a timeout Watch
+	// would hang forever on that GVR. When the timeout elapses, Watch falls
+	// back to polling that GVR with List every PollInterval instead.
+	WatchTimeout time.Duration
+
+	// PollInterval is how often a GVR that fell back to polling (see
+	// WatchTimeout) re-lists the apiserver to synthesize Added/Modified/
+	// Deleted events.
+	PollInterval time.Duration
+
+	// Polled is incremented every time a GVR falls back to polling because
+	// its informer failed to sync within WatchTimeout.
+	Polled int64
+}
+
+// NewWatchOptions returns a WatchOptions with the same defaults AddFlags
+// would set if the user passed no flags.
+func NewWatchOptions() *WatchOptions {
+	return &WatchOptions{
+		ResyncPeriod:    10 * time.Minute,
+		EventBufferSize: 100,
+		WatchTimeout:    30 * time.Second,
+		PollInterval:    5 * time.Second,
+	}
+}
+
+// AddFlags registers flags for tuning the streaming/informer behavior.
+func (o *WatchOptions) AddFlags(flagset *pflag.FlagSet) {
+	flagset.DurationVar(&o.ResyncPeriod, "resync-period", o.ResyncPeriod, "How often to relist watched resources against the apiserver, in addition to streaming incremental changes.")
+	flagset.DurationVar(&o.WatchTimeout, "watch-sync-timeout", o.WatchTimeout, "How long to wait for a resource's informer to establish a watch before falling back to polling it with --poll-interval. Resources that don't support watch (e.g. some aggregated APIs) would otherwise hang here forever.")
+	flagset.DurationVar(&o.PollInterval, "poll-interval", o.PollInterval, "How often to re-list a resource that fell back to polling because it doesn't support watch.")
+}
+
+// streamingResourceFinder wraps a ResourceFinder with informer-backed
+// watch support. It's returned by ResourceBuilderFlags.ToStreamingBuilder.
+type streamingResourceFinder struct {
+	ResourceFinder
+
+	dynamicClient dynamic.Interface
+	namespace     string
+	allNamespaces bool
+	labelSelector string
+	fieldSelector string
+	watchOpts     *WatchOptions
+}
+
+// ToStreamingBuilder is like ToBuilder, but the returned ResourceFinder also
+// implements StreamingResourceFinder: calling Watch() discovers the GVRs
+// matched by a first pass of Do(), and informers on each.
+func (o *ResourceBuilderFlags) ToStreamingBuilder(restClientGetter RESTClientGetter, resources []string, watchOpts *WatchOptions) (StreamingResourceFinder, error) {
+	if watchOpts == nil {
+		watchOpts = NewWatchOptions()
+	}
+	config, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("building streaming client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("building streaming client: %v", err)
+	}
+	namespace, _, err := restClientGetter.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+
+	finder := o.ToBuilder(restClientGetter, resources)
+	allNamespaces := o.AllNamespaces != nil && *o.AllNamespaces
+	labelSelector, fieldSelector := "", ""
+	if o.LabelSelector != nil {
+		labelSelector = *o.LabelSelector
+	}
+	if o.FieldSelector != nil {
+		fieldSelector = *o.FieldSelector
+	}
+
+	return &streamingResourceFinder{
+		ResourceFinder: finder,
+		dynamicClient:  dynamicClient,
+		namespace:      namespace,
+		allNamespaces:  allNamespaces,
+		labelSelector:  labelSelector,
+		fieldSelector:  fieldSelector,
+		watchOpts:      watchOpts,
+	}, nil
+}
+
+// Watch implements StreamingResourceFinder.
+func (f *streamingResourceFinder) Watch(stopCh <-chan struct{}) (<-chan WatchEvent, error) {
+	gvrs, err := f.discoverGVRs()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan WatchEvent, f.watchOpts.EventBufferSize)
+	namespace := f.namespace
+	if f.allNamespaces {
+		namespace = ""
+	}
+
+	var wg sync.WaitGroup
+	for gvr := range gvrs {
+		gvr := gvr
+		informerStop := make(chan struct{})
+		var stopInformerOnce sync.Once
+		stopInformer := func() { stopInformerOnce.Do(func() { close(informerStop) }) }
+		go func() {
+			<-stopCh
+			stopInformer()
+		}()
+
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(f.dynamicClient, f.watchOpts.ResyncPeriod, namespace, func(opts *metav1.ListOptions) {
+			opts.LabelSelector = f.labelSelector
+			opts.FieldSelector = f.fieldSelector
+		})
+		informer := factory.ForResource(gvr).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    f.forward(out, gvr, watch.Added),
+			UpdateFunc: func(_, newObj interface{}) { f.forward(out, gvr, watch.Modified)(newObj) },
+			DeleteFunc: f.forward(out, gvr, watch.Deleted),
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			informer.Run(informerStop)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.waitThenPollIfUnsynced(gvr, informer, informerStop, stopInformer, namespace, out, stopCh)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// waitThenPollIfUnsynced waits up to WatchTimeout for informer to complete
+// its initial sync. Most resources support watch and sync quickly, so this
+// returns almost immediately. If the apiserver doesn't support watch on gvr,
+// though, the informer's ListAndWatch loop never establishes a watch and
+// HasSynced never becomes true; once the timeout elapses this stops the
+// informer and polls gvr with List every PollInterval instead, synthesizing
+// Added/Modified/Deleted events from successive snapshots.
+func (f *streamingResourceFinder) waitThenPollIfUnsynced(gvr schema.GroupVersionResource, informer cache.SharedIndexInformer, informerStop <-chan struct{}, stopInformer func(), namespace string, out chan<- WatchEvent, stopCh <-chan struct{}) {
+	timeout := time.After(f.watchOpts.WatchTimeout)
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-informerStop:
+			return
+		case <-tick.C:
+			if informer.HasSynced() {
+				return
+			}
+		case <-timeout:
+			if informer.HasSynced() {
+				return
+			}
+			atomic.AddInt64(&f.watchOpts.Polled, 1)
+			stopInformer()
+			f.poll(gvr, namespace, out, stopCh)
+			return
+		}
+	}
+}
+
+// poll re-lists gvr every PollInterval and diffs each snapshot against the
+// last one, emitting synthetic Added/Modified/Deleted WatchEvents. It's the
+// fallback used for resources whose apiserver doesn't support watch.
+func (f *streamingResourceFinder) poll(gvr schema.GroupVersionResource, namespace string, out chan<- WatchEvent, stopCh <-chan struct{}) {
+	var client dynamic.ResourceInterface = f.dynamicClient.Resource(gvr)
+	if namespace != "" {
+		client = f.dynamicClient.Resource(gvr).Namespace(namespace)
+	}
+
+	seen := map[pollFallbackKey]*unstructured.Unstructured{}
+	listAndDiff := func() {
+		list, err := client.List(metav1.ListOptions{
+			LabelSelector: f.labelSelector,
+			FieldSelector: f.fieldSelector,
+		})
+		if err != nil {
+			return
+		}
+		current := map[pollFallbackKey]*unstructured.Unstructured{}
+		for i := range list.Items {
+			item := &list.Items[i]
+			key := pollFallbackKey{namespace: item.GetNamespace(), name: item.GetName()}
+			current[key] = item
+			if prev, ok := seen[key]; !ok {
+				f.forward(out, gvr, watch.Added)(item)
+			} else if prev.GetResourceVersion() != item.GetResourceVersion() {
+				f.forward(out, gvr, watch.Modified)(item)
+			}
+		}
+		for key, prev := range seen {
+			if _, ok := current[key]; !ok {
+				f.forward(out, gvr, watch.Deleted)(prev)
+			}
+		}
+		seen = current
+	}
+
+	listAndDiff()
+	ticker := time.NewTicker(f.watchOpts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			listAndDiff()
+		}
+	}
+}
+
+// forward returns an informer event handler that wraps obj as a WatchEvent
+// and sends it on out, dropping (and counting) the event if out is full
+// rather than blocking the informer's processing loop.
+func (f *streamingResourceFinder) forward(out chan<- WatchEvent, gvr schema.GroupVersionResource, eventType watch.EventType) func(interface{}) {
+	return func(obj interface{}) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		info := &resource.Info{
+			Namespace: u.GetNamespace(),
+			Name:      u.GetName(),
+			Object:    u,
+		}
+		select {
+		case out <- WatchEvent{Type: eventType, Info: info}:
+		default:
+			atomic.AddInt64(&f.watchOpts.Dropped, 1)
+		}
+	}
+}
+
+// discoverGVRs runs a single Do() pass over the wrapped ResourceFinder to
+// see which GroupVersionResources it actually matched, so Watch only starts
+// informers for resource kinds the user's query touches, deduped across
+// repeated kinds.
+func (f *streamingResourceFinder) discoverGVRs() (map[schema.GroupVersionResource]bool, error) {
+	gvrs := map[schema.GroupVersionResource]bool{}
+	err := f.ResourceFinder.Do().Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		gvrs[info.Mapping.Resource] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(gvrs) == 0 {
+		return nil, fmt.Errorf("no resources matched; nothing to watch")
+	}
+	return gvrs, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WorkloadGenerator is implemented by every `kubectl create <kind>`
+// generator that shares the Deployment-style name+images+labels+replicas
+// parameter set: Deployment, StatefulSet, DaemonSet, Job, CronJob, and
+// registered CRDs. StructuredGenerate builds the concrete object;
+// GroupVersionKind identifies which one so callers can report what was
+// created.
+type WorkloadGenerator interface {
+	StructuredGenerate() (runtime.Object, error)
+	GroupVersionKind() string
+}
+
+// makePodSpec turns a list of image references into a PodSpec with one
+// container per image, deriving each container's name the same way `docker
+// run` does: the last path segment of the image reference, with any
+// ":tag" or "@digest" suffix stripped.
+func makePodSpec(images []string) (v1.PodSpec, error) {
+	podSpec := v1.PodSpec{}
+	for _, image := range images {
+		name, err := containerNameForImage(image)
+		if err != nil {
+			return v1.PodSpec{}, err
+		}
+		podSpec.Containers = append(podSpec.Containers, v1.Container{
+			Name:  name,
+			Image: image,
+		})
+	}
+	return podSpec, nil
+}
+
+// containerNameForImage derives a container name from an image reference,
+// e.g. "reg/repo/image1:tag" -> "image1".
+func containerNameForImage(image string) (string, error) {
+	parts := strings.Split(image, "/")
+	name := parts[len(parts)-1]
+	name = strings.SplitN(name, "@", 2)[0]
+	tagParts := strings.Split(name, ":")
+	if len(tagParts) > 2 {
+		return "", fmt.Errorf("invalid image name %q: more than one ':'", image)
+	}
+	return tagParts[0], nil
+}
+
+// labelsForName returns the single "app" label every workload generator in
+// this file puts on its object and pod template, and uses as its selector
+// where the kind has one.
+func labelsForName(name string) map[string]string {
+	return map[string]string{"app": name}
+}
+
+// StatefulSetGeneratorV1 generates an apps/v1 StatefulSet.
+type StatefulSetGeneratorV1 struct {
+	BaseDeploymentGenerator
+	ServiceName string
+	Replicas    int32
+}
+
+var _ WorkloadGenerator = &StatefulSetGeneratorV1{}
+
+// StructuredGenerate outputs a StatefulSet object using the configured fields.
+func (s StatefulSetGeneratorV1) StructuredGenerate() (runtime.Object, error) {
+	template, err := s.structuredGenerate()
+	if err != nil {
+		return nil, err
+	}
+	replicas := s.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   s.Name,
+			Labels: template.Labels,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    replicasPtr(replicas),
+			ServiceName: s.ServiceName,
+			Selector:    &metav1.LabelSelector{MatchLabels: template.Labels},
+			Template:    *template,
+		},
+	}, nil
+}
+
+// GroupVersionKind implements WorkloadGenerator.
+func (s StatefulSetGeneratorV1) GroupVersionKind() string {
+	return "apps/v1, Kind=StatefulSet"
+}
+
+// DaemonSetGeneratorV1 generates an apps/v1 DaemonSet.
+type DaemonSetGeneratorV1 struct {
+	BaseDeploymentGenerator
+}
+
+var _ WorkloadGenerator = &DaemonSetGeneratorV1{}
+
+// StructuredGenerate outputs a DaemonSet object using the configured fields.
+func (s DaemonSetGeneratorV1) StructuredGenerate() (runtime.Object, error) {
+	template, err := s.structuredGenerate()
+	if err != nil {
+		return nil, err
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   s.Name,
+			Labels: template.Labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: template.Labels},
+			Template: *template,
+		},
+	}, nil
+}
+
+// GroupVersionKind implements WorkloadGenerator.
+func (s DaemonSetGeneratorV1) GroupVersionKind() string {
+	return "apps/v1, Kind=DaemonSet"
+}
+
+// JobGeneratorV1 generates a batch/v1 Job.
+type JobGeneratorV1 struct {
+	BaseDeploymentGenerator
+}
+
+var _ WorkloadGenerator = &JobGeneratorV1{}
+
+// StructuredGenerate outputs a Job object using the configured fields.
+func (s JobGeneratorV1) StructuredGenerate() (runtime.Object, error) {
+	template, err := s.structuredGenerate()
+	if err != nil {
+		return nil, err
+	}
+	template.Spec.RestartPolicy = v1.RestartPolicyNever
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   s.Name,
+			Labels: template.Labels,
+		},
+		Spec: batchv1.JobSpec{
+			Template: *template,
+		},
+	}, nil
+}
+
+// GroupVersionKind implements WorkloadGenerator.
+func (s JobGeneratorV1) GroupVersionKind() string {
+	return "batch/v1, Kind=Job"
+}
+
+// CronJobGeneratorV1 generates a batch/v1beta1 CronJob.
+type CronJobGeneratorV1 struct {
+	BaseDeploymentGenerator
+	Schedule string
+}
+
+var _ WorkloadGenerator = &CronJobGeneratorV1{}
+
+// StructuredGenerate outputs a CronJob object using the configured fields.
+func (s CronJobGeneratorV1) StructuredGenerate() (runtime.Object, error) {
+	if len(s.Schedule) == 0 {
+		return nil, fmt.Errorf("schedule must be specified")
+	}
+	template, err := s.structuredGenerate()
+	if err != nil {
+		return nil, err
+	}
+	template.Spec.RestartPolicy = v1.RestartPolicyNever
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   s.Name,
+			Labels: template.Labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule: s.Schedule,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: *template,
+				},
+			},
+		},
+	}, nil
+}
+
+// GroupVersionKind implements WorkloadGenerator.
+func (s CronJobGeneratorV1) GroupVersionKind() string {
+	return "batch/v1beta1, Kind=CronJob"
+}
+
+// CRDWorkloadTemplate registers a CRD as a target for
+// CRDWorkloadGeneratorV1, keyed by GVK.String() (e.g.
+// "example.com/v1, Kind=Foo"), mirroring the
+// sample-controller pattern of user-defined types: the template is a zero
+// value of the caller's generated Go type with a DeepCopyObject method, and
+// FillPodTemplate is responsible for wiring the shared PodTemplateSpec into
+// whatever field that type expects it (e.g. .Spec.Template).
+type CRDWorkloadTemplate struct {
+	GVK             schema.GroupVersionKind
+	Template        runtime.Object
+	FillPodTemplate func(obj runtime.Object, name string, labels map[string]string, template v1.PodTemplateSpec) error
+}
+
+var crdWorkloadTemplates = map[string]CRDWorkloadTemplate{}
+
+// RegisterCRDWorkload makes a CRD usable with a CRDWorkloadGeneratorV1
+// whose GVK equals t.GVK. No kubectl command in this package exposes it.
+// Plugins call this from an init function, the same way client-go codegen
+// registers a Scheme.
+func RegisterCRDWorkload(t CRDWorkloadTemplate) {
+	crdWorkloadTemplates[t.GVK.String()] = t
+}
+
+// CRDWorkloadGeneratorV1 generates an instance of a CRD registered via
+// RegisterCRDWorkload.
+type CRDWorkloadGeneratorV1 struct {
+	BaseDeploymentGenerator
+	GVK schema.GroupVersionKind
+}
+
+var _ WorkloadGenerator = &CRDWorkloadGeneratorV1{}
+
+// StructuredGenerate outputs an instance of the registered CRD, with its pod
+// template filled in via the registered FillPodTemplate hook.
+func (s CRDWorkloadGeneratorV1) StructuredGenerate() (runtime.Object, error) {
+	registered, ok := crdWorkloadTemplates[s.GVK.String()]
+	if !ok {
+		return nil, fmt.Errorf("no workload template registered for %s; call kubectl.RegisterCRDWorkload first", s.GVK.String())
+	}
+	template, err := s.structuredGenerate()
+	if err != nil {
+		return nil, err
+	}
+	obj := registered.Template.DeepCopyObject()
+	if err := registered.FillPodTemplate(obj, s.Name, template.Labels, *template); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GroupVersionKind implements WorkloadGenerator.
+func (s CRDWorkloadGeneratorV1) GroupVersionKind() string {
+	return s.GVK.String()
+}
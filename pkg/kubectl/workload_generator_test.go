@@ -0,0 +1,330 @@
+package kubectl
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newInt32(val int) *int32 {
+	p := new(int32)
+	*p = int32(val)
+	return p
+}
+
+// TestDeploymentBasicAppsGeneratorV1 is the table TestStructuredGenerate used
+// to run against a single zero-value generator shared across all cases
+// (never populated per-case, so it only ever exercised the "name must be
+// specified" error path). Rewritten here, constructing a fresh generator per
+// case, to actually exercise the v1beta1 generator it's named after.
+func TestDeploymentBasicAppsGeneratorV1(t *testing.T) {
+	tests := []struct {
+		generator DeploymentBasicAppsGeneratorV1
+		expected  *appsv1beta1.Deployment
+		expectErr bool
+	}{
+		{
+			generator: DeploymentBasicAppsGeneratorV1{},
+			expectErr: true, // no name, no images
+		},
+		{
+			generator: DeploymentBasicAppsGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "dep", Images: []string{}},
+			},
+			expectErr: true, // at least one image must be specified
+		},
+		{
+			generator: DeploymentBasicAppsGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "", Images: []string{"image1"}},
+			},
+			expectErr: true, // name must be specified
+		},
+		{
+			generator: DeploymentBasicAppsGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "dep1", Images: []string{"image1"}},
+			},
+			expected: &appsv1beta1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "dep1",
+					Labels: map[string]string{"app": "dep1"},
+				},
+				Spec: appsv1beta1.DeploymentSpec{
+					Replicas: newInt32(1),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "dep1"}},
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "dep1"}},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{{Name: "image1", Image: "image1"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			generator: DeploymentBasicAppsGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "dep1", Images: []string{"image1", "image2"}},
+			},
+			expected: &appsv1beta1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "dep1",
+					Labels: map[string]string{"app": "dep1"},
+				},
+				Spec: appsv1beta1.DeploymentSpec{
+					Replicas: newInt32(1),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "dep1"}},
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "dep1"}},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{Name: "image1", Image: "image1"},
+								{Name: "image2", Image: "image2"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		obj, err := test.generator.StructuredGenerate()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%+v: expected error and didn't get one", test.generator)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", test.generator, err)
+			continue
+		}
+		if !reflect.DeepEqual(obj, test.expected) {
+			t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", test.expected, obj)
+		}
+	}
+}
+
+func TestDeploymentGeneratorV1(t *testing.T) {
+	tests := []struct {
+		generator DeploymentGeneratorV1
+		expected  *appsv1.Deployment
+		expectErr bool
+	}{
+		{
+			generator: DeploymentGeneratorV1{},
+			expectErr: true,
+		},
+		{
+			generator: DeploymentGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "dep1", Images: []string{"image1"}},
+			},
+			expected: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "dep1",
+					Labels: map[string]string{"app": "dep1"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: newInt32(1),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "dep1"}},
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "dep1"}},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{{Name: "image1", Image: "image1"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			generator: DeploymentGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "dep1", Images: []string{"image1"}},
+				Replicas:                3,
+			},
+			expected: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "dep1",
+					Labels: map[string]string{"app": "dep1"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: newInt32(3),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "dep1"}},
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "dep1"}},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{{Name: "image1", Image: "image1"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		obj, err := test.generator.StructuredGenerate()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%+v: expected error and didn't get one", test.generator)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", test.generator, err)
+			continue
+		}
+		if !reflect.DeepEqual(obj, test.expected) {
+			t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", test.expected, obj)
+		}
+	}
+}
+
+func TestStatefulSetGeneratorV1(t *testing.T) {
+	generator := StatefulSetGeneratorV1{
+		BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "web", Images: []string{"image1"}},
+		ServiceName:             "web-svc",
+		Replicas:                2,
+	}
+	obj, err := generator.StructuredGenerate()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "web",
+			Labels: map[string]string{"app": "web"},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    newInt32(2),
+			ServiceName: "web-svc",
+			Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "image1", Image: "image1"}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", expected, obj)
+	}
+}
+
+func TestDaemonSetGeneratorV1(t *testing.T) {
+	generator := DaemonSetGeneratorV1{
+		BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "ds1", Images: []string{"image1"}},
+	}
+	obj, err := generator.StructuredGenerate()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "ds1",
+			Labels: map[string]string{"app": "ds1"},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ds1"}},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "ds1"}},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "image1", Image: "image1"}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", expected, obj)
+	}
+}
+
+func TestJobGeneratorV1(t *testing.T) {
+	generator := JobGeneratorV1{
+		BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "job1", Images: []string{"image1"}},
+	}
+	obj, err := generator.StructuredGenerate()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "job1",
+			Labels: map[string]string{"app": "job1"},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "job1"}},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					Containers:    []v1.Container{{Name: "image1", Image: "image1"}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", expected, obj)
+	}
+}
+
+func TestCronJobGeneratorV1(t *testing.T) {
+	tests := []struct {
+		generator CronJobGeneratorV1
+		expectErr bool
+	}{
+		{
+			generator: CronJobGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "cj1", Images: []string{"image1"}},
+			},
+			expectErr: true, // no Schedule
+		},
+		{
+			generator: CronJobGeneratorV1{
+				BaseDeploymentGenerator: BaseDeploymentGenerator{Name: "cj1", Images: []string{"image1"}},
+				Schedule:                "*/1 * * * *",
+			},
+			expectErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		obj, err := test.generator.StructuredGenerate()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%+v: expected error and didn't get one", test.generator)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", test.generator, err)
+			continue
+		}
+		expected := &batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "cj1",
+				Labels: map[string]string{"app": "cj1"},
+			},
+			Spec: batchv1beta1.CronJobSpec{
+				Schedule: "*/1 * * * *",
+				JobTemplate: batchv1beta1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						Template: v1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "cj1"}},
+							Spec: v1.PodSpec{
+								RestartPolicy: v1.RestartPolicyNever,
+								Containers:    []v1.Container{{Name: "image1", Image: "image1"}},
+							},
+						},
+					},
+				},
+			},
+		}
+		if !reflect.DeepEqual(obj, expected) {
+			t.Errorf("\nexpected:\n%#v\nsaw:\n%#v", expected, obj)
+		}
+	}
+}
@@ -18,11 +18,13 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -35,10 +37,20 @@ const (
 	StatusTooManyRequests = 429
 )
 
+// CauseTypeFieldManagerConflict is a cause type for a server-side apply
+// conflict: it marks a StatusCause whose Field is the path in contention and
+// whose Message names the field manager that currently owns it.
+const CauseTypeFieldManagerConflict metav1.CauseType = "FieldManagerConflict"
+
 // StatusError is an error intended for consumption by a REST API server; it can also be
 // reconstructed by clients from a REST response. Public to allow easy type switches.
 type StatusError struct {
 	ErrStatus metav1.Status
+
+	// cause is the original error passed to a constructor like
+	// NewForbidden or NewInternalError, folded into ErrStatus.Message as
+	// a string today but preserved here so it survives errors.Unwrap.
+	cause error
 }
 
 // APIStatus is exposed by errors that can be converted to an api.Status object
@@ -60,6 +72,43 @@ func (e *StatusError) Status() metav1.Status {
 	return e.ErrStatus
 }
 
+// Unwrap returns the error originally passed to the constructor that built
+// e (e.g. the err argument to NewForbidden or NewInternalError), so
+// errors.Unwrap and errors.As can reach it through e.
+func (e *StatusError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is a *StatusError with the same Reason as e,
+// so sentinels like ErrNotFound work with errors.Is(err, ErrNotFound)
+// instead of requiring callers to know about IsNotFound et al.
+func (e *StatusError) Is(target error) bool {
+	t, ok := target.(*StatusError)
+	if !ok {
+		return false
+	}
+	if e == nil || t == nil {
+		return e == t
+	}
+	return e.ErrStatus.Reason == t.ErrStatus.Reason
+}
+
+// Sentinel StatusErrors for use with errors.Is, e.g.
+// errors.Is(err, apierrors.ErrNotFound). Only Reason is compared, so these
+// match any StatusError sharing that reason regardless of the resource or
+// message involved -- the same granularity as the existing IsNotFound et
+// al. helpers, which remain the preferred spelling for code that doesn't
+// otherwise need Go 1.13 error wrapping.
+var (
+	ErrNotFound        = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonNotFound}}
+	ErrAlreadyExists   = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonAlreadyExists}}
+	ErrConflict        = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonConflict}}
+	ErrForbidden       = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonForbidden}}
+	ErrInvalid         = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonInvalid}}
+	ErrTimeout         = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonTimeout}}
+	ErrTooManyRequests = &StatusError{ErrStatus: metav1.Status{Reason: apiserver.StatusReasonTooManyRequests}}
+)
+
 // DebugError reports extended info about the error to debug output.
 func (e *StatusError) DebugError() (string, []interface{}) {
 	if out, err := json.MarshalIndent(e.ErrStatus, "", "  "); err == nil {
@@ -83,14 +132,36 @@ func (u *UnexpectedObjectError) Error() string {
 func FromObject(obj runtime.Object) error {
 	switch t := obj.(type) {
 	case *metav1.Status:
-		return &StatusError{*t}
+		return &StatusError{ErrStatus: *t}
+	case runtime.Unstructured:
+		return statusFromUnstructured(t, obj)
 	}
 	return &UnexpectedObjectError{obj}
 }
 
+// statusFromUnstructured builds a StatusError out of an unstructured object,
+// as returned by dynamic clients and controller-runtime, if its kind and
+// apiVersion identify it as a Status. obj is only used for the
+// UnexpectedObjectError fallback so the returned error still carries the
+// original runtime.Object.
+func statusFromUnstructured(u runtime.Unstructured, obj runtime.Object) error {
+	content := u.UnstructuredContent()
+	kind, _, _ := unstructured.NestedString(content, "kind")
+	apiVersion, _, _ := unstructured.NestedString(content, "apiVersion")
+	if kind != "Status" || (apiVersion != "v1" && apiVersion != "meta.k8s.io/v1") {
+		return &UnexpectedObjectError{obj}
+	}
+
+	var status metav1.Status
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &status); err != nil {
+		return err
+	}
+	return &StatusError{ErrStatus: status}
+}
+
 // NewNotFound returns a new error which indicates that the resource of the kind and the name was not found.
 func NewNotFound(qualifiedResource schema.GroupResource, name string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusNotFound,
 		Reason: apiserver.StatusReasonNotFound,
@@ -105,7 +176,7 @@ func NewNotFound(qualifiedResource schema.GroupResource, name string) *StatusErr
 
 // NewAlreadyExists returns an error indicating the item requested exists by that identifier.
 func NewAlreadyExists(qualifiedResource schema.GroupResource, name string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusConflict,
 		Reason: apiserver.StatusReasonAlreadyExists,
@@ -125,7 +196,7 @@ func NewUnauthorized(reason string) *StatusError {
 	if len(message) == 0 {
 		message = "not authorized"
 	}
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusUnauthorized,
 		Reason:  apiserver.StatusReasonUnauthorized,
@@ -143,37 +214,58 @@ func NewForbidden(qualifiedResource schema.GroupResource, name string, err error
 	} else {
 		message = fmt.Sprintf("%s %q is forbidden: %v", qualifiedResource.String(), name, err)
 	}
-	return &StatusError{metav1.Status{
-		Status: metav1.StatusFailure,
-		Code:   http.StatusForbidden,
-		Reason: apiserver.StatusReasonForbidden,
-		Details: &metav1.StatusDetails{
-			Group: qualifiedResource.Group,
-			Kind:  qualifiedResource.Resource,
-			Name:  name,
+	return &StatusError{
+		ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   http.StatusForbidden,
+			Reason: apiserver.StatusReasonForbidden,
+			Details: &metav1.StatusDetails{
+				Group: qualifiedResource.Group,
+				Kind:  qualifiedResource.Resource,
+				Name:  name,
+			},
+			Message: message,
 		},
-		Message: message,
-	}}
+		cause: err,
+	}
 }
 
 // NewConflict returns an error indicating the item can't be updated as provided.
 func NewConflict(qualifiedResource schema.GroupResource, name string, err error) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{
+		ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   http.StatusConflict,
+			Reason: apiserver.StatusReasonConflict,
+			Details: &metav1.StatusDetails{
+				Group: qualifiedResource.Group,
+				Kind:  qualifiedResource.Resource,
+				Name:  name,
+			},
+			Message: fmt.Sprintf("Operation cannot be fulfilled on %s %q: %v", qualifiedResource.String(), name, err),
+		},
+		cause: err,
+	}
+}
+
+// NewApplyConflict returns an error indicating that the server-side apply
+// request conflicted with existing field ownership, with causes describing
+// which fields and which field managers are in contention.
+func NewApplyConflict(causes []metav1.StatusCause, message string) *StatusError {
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusConflict,
 		Reason: apiserver.StatusReasonConflict,
 		Details: &metav1.StatusDetails{
-			Group: qualifiedResource.Group,
-			Kind:  qualifiedResource.Resource,
-			Name:  name,
+			Causes: causes,
 		},
-		Message: fmt.Sprintf("Operation cannot be fulfilled on %s %q: %v", qualifiedResource.String(), name, err),
+		Message: message,
 	}}
 }
 
 // NewGone returns an error indicating the item no longer available at the server and no forwarding address is known.
 func NewGone(message string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusGone,
 		Reason:  apiserver.StatusReasonGone,
@@ -184,7 +276,7 @@ func NewGone(message string) *StatusError {
 // NewResourceExpired creates an error that indicates that the requested resource content has expired from
 // the server (usually due to a resourceVersion that is too old).
 func NewResourceExpired(message string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusGone,
 		Reason:  apiserver.StatusReasonExpired,
@@ -203,7 +295,7 @@ func NewInvalid(qualifiedKind schema.GroupKind, name string, errs field.ErrorLis
 			Field:   err.Field,
 		})
 	}
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusUnprocessableEntity,
 		Reason: apiserver.StatusReasonInvalid,
@@ -219,7 +311,7 @@ func NewInvalid(qualifiedKind schema.GroupKind, name string, errs field.ErrorLis
 
 // NewBadRequest creates an error that indicates that the request is invalid and can not be processed.
 func NewBadRequest(reason string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusBadRequest,
 		Reason:  apiserver.StatusReasonBadRequest,
@@ -231,7 +323,7 @@ func NewBadRequest(reason string) *StatusError {
 // the specified endpoint is not accepting requests. More specific details should be provided
 // if client should know why the failure was limited4.
 func NewTooManyRequests(message string, retryAfterSeconds int) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusTooManyRequests,
 		Reason:  apiserver.StatusReasonTooManyRequests,
@@ -244,7 +336,7 @@ func NewTooManyRequests(message string, retryAfterSeconds int) *StatusError {
 
 // NewServiceUnavailable creates an error that indicates that the requested service is unavailable.
 func NewServiceUnavailable(reason string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusServiceUnavailable,
 		Reason:  apiserver.StatusReasonServiceUnavailable,
@@ -254,7 +346,7 @@ func NewServiceUnavailable(reason string) *StatusError {
 
 // NewMethodNotSupported returns an error indicating the requested action is not supported on this kind.
 func NewMethodNotSupported(qualifiedResource schema.GroupResource, action string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusMethodNotAllowed,
 		Reason: apiserver.StatusReasonMethodNotAllowed,
@@ -269,7 +361,7 @@ func NewMethodNotSupported(qualifiedResource schema.GroupResource, action string
 // NewServerTimeout returns an error indicating the requested action could not be completed due to a
 // transient error, and the client should try again.
 func NewServerTimeout(qualifiedResource schema.GroupResource, operation string, retryAfterSeconds int) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   http.StatusInternalServerError,
 		Reason: apiserver.StatusReasonServerTimeout,
@@ -291,21 +383,24 @@ func NewServerTimeoutForKind(qualifiedKind schema.GroupKind, operation string, r
 
 // NewInternalError returns an error indicating the item is invalid and cannot be processed.
 func NewInternalError(err error) *StatusError {
-	return &StatusError{metav1.Status{
-		Status: metav1.StatusFailure,
-		Code:   http.StatusInternalServerError,
-		Reason: apiserver.StatusReasonInternalError,
-		Details: &metav1.StatusDetails{
-			Causes: []metav1.StatusCause{{Message: err.Error()}},
+	return &StatusError{
+		ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   http.StatusInternalServerError,
+			Reason: apiserver.StatusReasonInternalError,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{Message: err.Error()}},
+			},
+			Message: fmt.Sprintf("Internal error occurred: %v", err),
 		},
-		Message: fmt.Sprintf("Internal error occurred: %v", err),
-	}}
+		cause: err,
+	}
 }
 
 // NewTimeoutError returns an error indicating that a timeout occurred before the request
 // could be completed.  Clients may retry, but the operation may still complete.
 func NewTimeoutError(message string, retryAfterSeconds int) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    http.StatusGatewayTimeout,
 		Reason:  apiserver.StatusReasonTimeout,
@@ -320,7 +415,7 @@ func NewTimeoutError(message string, retryAfterSeconds int) *StatusError {
 // the server has received too many requests. Client should wait and retry. But if the request
 // is perishable, then the client should not retry the request.
 func NewTooManyRequestsError(message string) *StatusError {
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status:  metav1.StatusFailure,
 		Code:    StatusTooManyRequests,
 		Reason:  apiserver.StatusReasonTooManyRequests,
@@ -399,7 +494,7 @@ func NewGenericServerResponse(code int, verb string, qualifiedResource schema.Gr
 	} else {
 		causes = nil
 	}
-	return &StatusError{metav1.Status{
+	return &StatusError{ErrStatus: metav1.Status{
 		Status: metav1.StatusFailure,
 		Code:   int32(code),
 		Reason: reason,
@@ -435,6 +530,36 @@ func IsInvalid(err error) bool {
 	return ReasonForError(err) == apiserver.StatusReasonInvalid
 }
 
+// IsApplyConflict determines if err is a Conflict raised because a
+// server-side apply request disagreed with another field manager's
+// ownership, as opposed to a plain optimistic-concurrency conflict. It
+// returns true only if at least one of the error's causes carries
+// CauseTypeFieldManagerConflict.
+func IsApplyConflict(err error) bool {
+	return len(ApplyConflictCauses(err)) > 0
+}
+
+// ApplyConflictCauses returns the field-manager conflict causes carried by
+// err, or nil if err isn't a Conflict or carries no such causes. Callers use
+// this to decide whether to retry with force-apply, and against which field
+// managers.
+func ApplyConflictCauses(err error) []metav1.StatusCause {
+	if ReasonForError(err) != apiserver.StatusReasonConflict {
+		return nil
+	}
+	var status APIStatus
+	if !stderrors.As(err, &status) || status.Status().Details == nil {
+		return nil
+	}
+	var causes []metav1.StatusCause
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == CauseTypeFieldManagerConflict {
+			causes = append(causes, cause)
+		}
+	}
+	return causes
+}
+
 // IsGone is true if the error indicates the requested resource is no longer available.
 func IsGone(err error) bool {
 	return ReasonForError(err) == apiserver.StatusReasonGone
@@ -539,30 +664,32 @@ func IsUnexpectedObjectError(err error) bool {
 // SuggestsClientDelay returns true if this error suggests a client delay as well as the
 // suggested seconds to wait, or false if the error does not imply a wait. It does not
 // address whether the error *should* be retried, since some errors (like a 3xx) may
-// request delay without retry.
+// request delay without retry. Like ReasonForError, it walks err's errors.Unwrap chain
+// so a StatusError wrapped by another error is still recognized.
 func SuggestsClientDelay(err error) (int, bool) {
-	switch t := err.(type) {
-	case APIStatus:
-		if t.Status().Details != nil {
-			switch t.Status().Reason {
-			// this StatusReason explicitly requests the caller to delay the action
-			case apiserver.StatusReasonServerTimeout:
-				return int(t.Status().Details.RetryAfterSeconds), true
-			}
-			// If the client requests that we retry after a certain number of seconds
-			if t.Status().Details.RetryAfterSeconds > 0 {
-				return int(t.Status().Details.RetryAfterSeconds), true
-			}
-		}
+	var status APIStatus
+	if !stderrors.As(err, &status) || status.Status().Details == nil {
+		return 0, false
+	}
+	switch status.Status().Reason {
+	// this StatusReason explicitly requests the caller to delay the action
+	case apiserver.StatusReasonServerTimeout:
+		return int(status.Status().Details.RetryAfterSeconds), true
+	}
+	// If the client requests that we retry after a certain number of seconds
+	if status.Status().Details.RetryAfterSeconds > 0 {
+		return int(status.Status().Details.RetryAfterSeconds), true
 	}
 	return 0, false
 }
 
-// ReasonForError returns the HTTP status for a particular error.
+// ReasonForError returns the HTTP status for a particular error. It walks
+// err's errors.Unwrap chain, so it also recognizes a StatusError wrapped by
+// another error along the way, not just err itself.
 func ReasonForError(err error) apiserver.StatusReason {
-	switch t := err.(type) {
-	case APIStatus:
-		return t.Status().Reason
+	var status APIStatus
+	if stderrors.As(err, &status) {
+		return status.Status().Reason
 	}
 	return apiserver.StatusReasonUnknown
 }
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/apis/apiserver"
+)
+
+func TestStatusErrorIs(t *testing.T) {
+	notFound := NewNotFound(schema.GroupResource{Resource: "pods"}, "foo")
+	conflict := NewConflict(schema.GroupResource{Resource: "pods"}, "foo", stderrors.New("boom"))
+
+	if !stderrors.Is(notFound, ErrNotFound) {
+		t.Errorf("expected %v to match ErrNotFound", notFound)
+	}
+	if stderrors.Is(notFound, ErrConflict) {
+		t.Errorf("expected %v not to match ErrConflict", notFound)
+	}
+	if !stderrors.Is(conflict, ErrConflict) {
+		t.Errorf("expected %v to match ErrConflict", conflict)
+	}
+
+	wrapped := fmt.Errorf("wrapping: %w", notFound)
+	if !stderrors.Is(wrapped, ErrNotFound) {
+		t.Errorf("expected wrapped error %v to match ErrNotFound", wrapped)
+	}
+}
+
+func TestStatusErrorUnwrap(t *testing.T) {
+	cause := stderrors.New("underlying cause")
+	err := NewForbidden(schema.GroupResource{Resource: "pods"}, "foo", cause)
+
+	if got := stderrors.Unwrap(err); got != cause {
+		t.Errorf("Unwrap() = %v, want %v", got, cause)
+	}
+
+	// A StatusError built by a constructor that takes no cause has
+	// nothing to unwrap.
+	bare := NewNotFound(schema.GroupResource{Resource: "pods"}, "foo")
+	if got := stderrors.Unwrap(bare); got != nil {
+		t.Errorf("Unwrap() = %v, want nil", got)
+	}
+}
+
+func TestApplyConflictCauses(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected []metav1.StatusCause
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: nil,
+		},
+		{
+			name:     "not a conflict",
+			err:      NewNotFound(schema.GroupResource{Resource: "pods"}, "foo"),
+			expected: nil,
+		},
+		{
+			name:     "conflict with no causes",
+			err:      NewConflict(schema.GroupResource{Resource: "pods"}, "foo", stderrors.New("boom")),
+			expected: nil,
+		},
+		{
+			name: "conflict with only non-field-manager causes",
+			err: NewApplyConflict([]metav1.StatusCause{
+				{Type: metav1.CauseType("FieldValueInvalid"), Field: "spec.replicas"},
+			}, "invalid"),
+			expected: nil,
+		},
+		{
+			name: "apply conflict",
+			err: NewApplyConflict([]metav1.StatusCause{
+				{Type: CauseTypeFieldManagerConflict, Field: "spec.replicas", Message: "kubectl"},
+				{Type: metav1.CauseType("FieldValueInvalid"), Field: "spec.replicas"},
+				{Type: CauseTypeFieldManagerConflict, Field: "spec.template", Message: "controller"},
+			}, "conflict"),
+			expected: []metav1.StatusCause{
+				{Type: CauseTypeFieldManagerConflict, Field: "spec.replicas", Message: "kubectl"},
+				{Type: CauseTypeFieldManagerConflict, Field: "spec.template", Message: "controller"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			causes := ApplyConflictCauses(test.err)
+			if len(causes) != len(test.expected) {
+				t.Fatalf("ApplyConflictCauses() = %v, want %v", causes, test.expected)
+			}
+			for i := range causes {
+				if causes[i] != test.expected[i] {
+					t.Errorf("cause %d = %v, want %v", i, causes[i], test.expected[i])
+				}
+			}
+			if want := len(test.expected) > 0; IsApplyConflict(test.err) != want {
+				t.Errorf("IsApplyConflict() = %v, want %v", !want, want)
+			}
+		})
+	}
+}
+
+func TestFromObjectUnstructured(t *testing.T) {
+	status := metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    404,
+		Reason:  apiserver.StatusReasonNotFound,
+		Message: "pods \"foo\" not found",
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		t.Fatalf("unexpected error converting status to unstructured: %v", err)
+	}
+	content["kind"] = "Status"
+	content["apiVersion"] = "v1"
+	u := &unstructured.Unstructured{Object: content}
+
+	got := FromObject(u)
+	statusErr, ok := got.(*StatusError)
+	if !ok {
+		t.Fatalf("FromObject() = %T, want *StatusError", got)
+	}
+	if statusErr.ErrStatus.Reason != apiserver.StatusReasonNotFound {
+		t.Errorf("ErrStatus.Reason = %v, want %v", statusErr.ErrStatus.Reason, apiserver.StatusReasonNotFound)
+	}
+	if statusErr.ErrStatus.Message != status.Message {
+		t.Errorf("ErrStatus.Message = %q, want %q", statusErr.ErrStatus.Message, status.Message)
+	}
+
+	// An unstructured object that isn't a Status falls back to
+	// UnexpectedObjectError.
+	notStatus := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":       "Pod",
+		"apiVersion": "v1",
+	}}
+	if _, ok := FromObject(notStatus).(*UnexpectedObjectError); !ok {
+		t.Errorf("FromObject(non-Status) = %T, want *UnexpectedObjectError", FromObject(notStatus))
+	}
+}
+
+func TestSuggestsClientDelayUnwraps(t *testing.T) {
+	serverTimeout := NewServerTimeout(schema.GroupResource{Resource: "pods"}, "get", 5)
+	wrapped := fmt.Errorf("wrapping: %w", serverTimeout)
+
+	seconds, ok := SuggestsClientDelay(wrapped)
+	if !ok || seconds != 5 {
+		t.Errorf("SuggestsClientDelay(wrapped) = (%d, %v), want (5, true)", seconds, ok)
+	}
+
+	if _, ok := SuggestsClientDelay(stderrors.New("plain error")); ok {
+		t.Errorf("SuggestsClientDelay(plain error) = true, want false")
+	}
+}
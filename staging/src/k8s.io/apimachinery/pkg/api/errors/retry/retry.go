@@ -0,0 +1,176 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry classifies the errors produced by the errors package into
+// retry decisions, and provides a small helper loop built on top of that
+// classification. It exists so that workqueues, informers, and ordinary
+// clients stop re-implementing the same backoff-around-SuggestsClientDelay
+// loop.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Action is the outcome of classifying an error for retry purposes.
+type Action int
+
+const (
+	// ActionFail means the error is not retriable; give up immediately.
+	ActionFail Action = iota
+	// ActionRetry means the error is retriable; the caller's own backoff
+	// should decide how long to wait.
+	ActionRetry
+	// ActionRetryAfter means the server told us how long to wait before
+	// retrying (via Retry-After / RetryAfterSeconds), and that delay
+	// should be used instead of the caller's computed backoff.
+	ActionRetryAfter
+)
+
+// Backoff configures the exponential backoff OnRetriable falls back to when
+// the server doesn't suggest a delay of its own.
+type Backoff struct {
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+	// MaxDelay caps both the exponential backoff and any server-suggested
+	// delay honored via ActionRetryAfter.
+	MaxDelay time.Duration
+	// Factor is multiplied into the delay after each attempt. A Factor
+	// of 0 is treated as 1 (no growth).
+	Factor float64
+	// Jitter, in [0,1), randomizes each delay by up to that fraction, to
+	// avoid every client retrying in lockstep.
+	Jitter float64
+	// MaxAttempts bounds how many times fn is called in total. A
+	// MaxAttempts of 0 means unlimited (bounded only by ctx).
+	MaxAttempts int
+}
+
+// next returns the delay before attempt (1-indexed), before jitter.
+func (b Backoff) next(attempt int) time.Duration {
+	factor := b.Factor
+	if factor <= 0 {
+		factor = 1
+	}
+	delay := float64(b.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+	}
+	d := time.Duration(delay)
+	if b.MaxDelay > 0 && d > b.MaxDelay {
+		d = b.MaxDelay
+	}
+	return d
+}
+
+func (b Backoff) jittered(d time.Duration) time.Duration {
+	if b.Jitter <= 0 {
+		return d
+	}
+	delta := float64(d) * b.Jitter
+	return d - time.Duration(delta) + time.Duration(rand.Float64()*2*delta)
+}
+
+// Classify decides whether err should be retried, and if the server told us
+// how long to wait, returns that as the ActionRetryAfter delay.
+func Classify(err error) (Action, time.Duration) {
+	if err == nil {
+		return ActionFail, 0
+	}
+
+	switch {
+	case apierrors.IsInvalid(err),
+		apierrors.IsBadRequest(err),
+		apierrors.IsForbidden(err),
+		apierrors.IsUnauthorized(err),
+		apierrors.IsNotFound(err),
+		apierrors.IsMethodNotSupported(err):
+		return ActionFail, 0
+	}
+
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+		return ActionRetryAfter, time.Duration(seconds) * time.Second
+	}
+
+	switch {
+	case apierrors.IsServerTimeout(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsTimeout(err):
+		return ActionRetry, 0
+	}
+
+	return ActionFail, 0
+}
+
+// OnRetriable calls fn until it succeeds, ctx is cancelled, Backoff's
+// MaxAttempts is exhausted, or Classify decides the error fn returned isn't
+// retriable. Between attempts it sleeps for the delay Classify suggests
+// (honoring a server-provided Retry-After over the exponential backoff),
+// capped by backoff.MaxDelay and subject to cancellation.
+func OnRetriable(ctx context.Context, backoff Backoff, fn func() error) error {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return wrapCancellation(err, lastErr)
+		}
+
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+
+		action, retryAfter := Classify(lastErr)
+		if action == ActionFail {
+			return lastErr
+		}
+		if backoff.MaxAttempts > 0 && attempt >= backoff.MaxAttempts {
+			return lastErr
+		}
+
+		delay := backoff.jittered(backoff.next(attempt))
+		if action == ActionRetryAfter {
+			delay = retryAfter
+		}
+		if backoff.MaxDelay > 0 && delay > backoff.MaxDelay {
+			delay = backoff.MaxDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return wrapCancellation(ctx.Err(), lastErr)
+		case <-timer.C:
+		}
+	}
+}
+
+// wrapCancellation wraps ctxErr so errors.Is(result, context.Canceled) (or
+// context.DeadlineExceeded) keeps working, while still surfacing the last
+// API error OnRetriable observed before ctx was cancelled.
+func wrapCancellation(ctxErr, lastErr error) error {
+	if lastErr == nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w (last observed error: %v)", ctxErr, lastErr)
+}
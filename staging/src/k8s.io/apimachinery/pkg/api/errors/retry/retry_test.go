@@ -0,0 +1,178 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassify(t *testing.T) {
+	resource := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name           string
+		err            error
+		expectedAction Action
+		expectedDelay  time.Duration
+	}{
+		{
+			name:           "nil error",
+			err:            nil,
+			expectedAction: ActionFail,
+		},
+		{
+			name:           "not found is not retriable",
+			err:            apierrors.NewNotFound(resource, "foo"),
+			expectedAction: ActionFail,
+		},
+		{
+			name:           "invalid is not retriable",
+			err:            apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "foo", nil),
+			expectedAction: ActionFail,
+		},
+		{
+			name:           "forbidden is not retriable",
+			err:            apierrors.NewForbidden(resource, "foo", stderrors.New("denied")),
+			expectedAction: ActionFail,
+		},
+		{
+			name:           "server timeout honors the suggested delay",
+			err:            apierrors.NewServerTimeout(resource, "get", 7),
+			expectedAction: ActionRetryAfter,
+			expectedDelay:  7 * time.Second,
+		},
+		{
+			name:           "too many requests is retriable",
+			err:            apierrors.NewTooManyRequestsError("slow down"),
+			expectedAction: ActionRetry,
+		},
+		{
+			name:           "service unavailable is retriable",
+			err:            apierrors.NewServiceUnavailable("down for maintenance"),
+			expectedAction: ActionRetry,
+		},
+		{
+			name:           "internal error is retriable",
+			err:            apierrors.NewInternalError(stderrors.New("boom")),
+			expectedAction: ActionRetry,
+		},
+		{
+			name:           "generic error is not retriable",
+			err:            stderrors.New("not a status error"),
+			expectedAction: ActionFail,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action, delay := Classify(test.err)
+			if action != test.expectedAction {
+				t.Errorf("Classify() action = %v, want %v", action, test.expectedAction)
+			}
+			if delay != test.expectedDelay {
+				t.Errorf("Classify() delay = %v, want %v", delay, test.expectedDelay)
+			}
+		})
+	}
+}
+
+func TestOnRetriableSucceedsWithoutRetry(t *testing.T) {
+	calls := 0
+	err := OnRetriable(context.Background(), Backoff{InitialDelay: time.Millisecond}, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestOnRetriableFailsImmediatelyOnNonRetriable(t *testing.T) {
+	calls := 0
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "foo")
+	err := OnRetriable(context.Background(), Backoff{InitialDelay: time.Millisecond}, func() error {
+		calls++
+		return notFound
+	})
+	if err != notFound {
+		t.Fatalf("err = %v, want %v", err, notFound)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestOnRetriableRespectsMaxAttempts(t *testing.T) {
+	calls := 0
+	retriable := apierrors.NewServiceUnavailable("down")
+	backoff := Backoff{InitialDelay: time.Millisecond, MaxAttempts: 3}
+	err := OnRetriable(context.Background(), backoff, func() error {
+		calls++
+		return retriable
+	})
+	if err != retriable {
+		t.Fatalf("err = %v, want %v", err, retriable)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3 (MaxAttempts)", calls)
+	}
+}
+
+func TestOnRetriableStopsOnContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	retriable := apierrors.NewServiceUnavailable("down")
+
+	calls := 0
+	err := OnRetriable(ctx, Backoff{InitialDelay: time.Hour}, func() error {
+		calls++
+		cancel()
+		return retriable
+	})
+
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+	if !stderrors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want it to wrap context.Canceled", err)
+	}
+}
+
+func TestOnRetriableReturnsContextErrorIfCancelledBeforeFirstAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := OnRetriable(ctx, Backoff{InitialDelay: time.Millisecond}, func() error {
+		calls++
+		return nil
+	})
+
+	if calls != 0 {
+		t.Errorf("calls = %d, want 0", calls)
+	}
+	if !stderrors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}